@@ -0,0 +1,188 @@
+package resp
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ArchiveEntry is a single file to be streamed into an archive written by
+// WriteZip or WriteTarGz. Either Reader or Open must be set; Open is used
+// when present, so callers that want to defer opening a file (or opening
+// many of them) until the archive actually reaches that entry should use it
+// instead of Reader.
+//
+// Size is required by WriteTarGz: unlike zip, the tar format writes a fixed
+// header before an entry's body, so the entry's length must be known up
+// front. WriteZip does not need it, since zip entries are streamed with a
+// trailing data descriptor.
+type ArchiveEntry struct {
+	Name    string
+	Mode    os.FileMode
+	ModTime time.Time
+	Size    int64
+	Reader  io.Reader
+	Open    func() (io.ReadCloser, error)
+}
+
+func (e ArchiveEntry) open() (io.ReadCloser, error) {
+	if e.Open != nil {
+		return e.Open()
+	}
+	if rc, ok := e.Reader.(io.ReadCloser); ok {
+		return rc, nil
+	}
+	return io.NopCloser(e.Reader), nil
+}
+
+// abort gives up on a response whose header has already been written with a
+// success status: there is no way to signal failure through the status line
+// any more, so the connection is closed instead of leaving a truncated body
+// that looks complete.
+func (r *Resp) abort(headerSent bool, err error) {
+	if !headerSent {
+		r.WriteError(err)
+		return
+	}
+	if hj, ok := r.w.(http.Hijacker); ok {
+		if conn, _, err := hj.Hijack(); err == nil {
+			conn.Close()
+		}
+	}
+}
+
+func WriteZip(w http.ResponseWriter, statusCode int, filename string, entries []ArchiveEntry) {
+	New(w).WriteZip(statusCode, filename, entries)
+}
+
+// WriteZip streams entries as a zip archive, without buffering the archive
+// in memory. It flushes after every entry so large archives start
+// downloading immediately.
+func (r *Resp) WriteZip(statusCode int, filename string, entries []ArchiveEntry) {
+	r.applyCORS()
+
+	r.w.Header().Set("Content-Type", "application/zip")
+	r.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	flusher, _ := r.w.(http.Flusher)
+	headerSent := false
+	zw := zip.NewWriter(r.w)
+
+	for _, entry := range entries {
+		rc, err := entry.open()
+		if err != nil {
+			r.abort(headerSent, err)
+			return
+		}
+
+		if !headerSent {
+			r.w.WriteHeader(statusCode)
+			headerSent = true
+		}
+
+		fh := &zip.FileHeader{Name: entry.Name, Modified: entry.ModTime}
+		fh.SetMode(entry.Mode)
+
+		fw, err := zw.CreateHeader(fh)
+		if err == nil {
+			_, err = io.Copy(fw, rc)
+		}
+		rc.Close()
+		if err != nil {
+			r.abort(headerSent, err)
+			return
+		}
+
+		if flusher != nil {
+			zw.Flush()
+			flusher.Flush()
+		}
+	}
+
+	if !headerSent {
+		r.w.WriteHeader(statusCode)
+		headerSent = true
+	}
+	if err := zw.Close(); err != nil {
+		r.abort(headerSent, err)
+		return
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+func WriteTarGz(w http.ResponseWriter, statusCode int, filename string, entries []ArchiveEntry) {
+	New(w).WriteTarGz(statusCode, filename, entries)
+}
+
+// WriteTarGz streams entries as a gzip-compressed tar archive, without
+// buffering the archive in memory. It flushes after every entry so large
+// archives start downloading immediately.
+func (r *Resp) WriteTarGz(statusCode int, filename string, entries []ArchiveEntry) {
+	r.applyCORS()
+
+	r.w.Header().Set("Content-Type", "application/x-gtar")
+	r.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	flusher, _ := r.w.(http.Flusher)
+	headerSent := false
+	gw := gzip.NewWriter(r.w)
+	tw := tar.NewWriter(gw)
+
+	for _, entry := range entries {
+		rc, err := entry.open()
+		if err != nil {
+			r.abort(headerSent, err)
+			return
+		}
+
+		if !headerSent {
+			r.w.WriteHeader(statusCode)
+			headerSent = true
+		}
+
+		err = tw.WriteHeader(&tar.Header{
+			Name:     entry.Name,
+			Mode:     int64(entry.Mode.Perm()),
+			Size:     entry.Size,
+			ModTime:  entry.ModTime,
+			Typeflag: tar.TypeReg,
+		})
+		if err == nil {
+			_, err = io.Copy(tw, rc)
+		}
+		rc.Close()
+		if err != nil {
+			r.abort(headerSent, err)
+			return
+		}
+
+		if flusher != nil {
+			tw.Flush()
+			gw.Flush()
+			flusher.Flush()
+		}
+	}
+
+	if !headerSent {
+		r.w.WriteHeader(statusCode)
+		headerSent = true
+	}
+	if err := tw.Close(); err != nil {
+		r.abort(headerSent, err)
+		return
+	}
+	if err := gw.Close(); err != nil {
+		r.abort(headerSent, err)
+		return
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
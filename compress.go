@@ -0,0 +1,128 @@
+package resp
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// compressibleTypePrefixes lists the Content-Type families worth spending
+// CPU compressing; binary formats (images, video, zip, ...) are already
+// compressed or incompressible and would just waste cycles.
+var compressibleTypePrefixes = []string{
+	"text/",
+	"application/json",
+	"application/xml",
+	"image/svg+xml",
+}
+
+func isCompressible(contentType string) bool {
+	mediaType := contentType
+	if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+		mediaType = mediaType[:i]
+	}
+	mediaType = strings.TrimSpace(mediaType)
+
+	for _, prefix := range compressibleTypePrefixes {
+		if strings.HasSuffix(prefix, "/") {
+			if strings.HasPrefix(mediaType, prefix) {
+				return true
+			}
+			continue
+		}
+		if mediaType == prefix {
+			return true
+		}
+	}
+
+	return false
+}
+
+// negotiateEncoding picks gzip or deflate from the request's Accept-Encoding
+// header, preferring gzip, and returns "" when neither is acceptable (absent,
+// or explicitly disabled with a "q=0" parameter).
+func negotiateEncoding(req *http.Request) string {
+	header := req.Header.Get("Accept-Encoding")
+	if header == "" {
+		return ""
+	}
+
+	gzipQ, deflateQ := 0.0, 0.0
+	for _, token := range strings.Split(header, ",") {
+		name, q := parseEncodingToken(token)
+		switch name {
+		case "gzip":
+			gzipQ = q
+		case "deflate":
+			deflateQ = q
+		}
+	}
+
+	switch {
+	case gzipQ > 0:
+		return "gzip"
+	case deflateQ > 0:
+		return "deflate"
+	}
+
+	return ""
+}
+
+// parseEncodingToken splits a single Accept-Encoding token ("gzip;q=0.5")
+// into its coding name and q-value, defaulting to q=1 when absent.
+func parseEncodingToken(token string) (name string, q float64) {
+	parts := strings.SplitN(token, ";", 2)
+	name = strings.TrimSpace(parts[0])
+	q = 1.0
+
+	if len(parts) == 2 {
+		if param := strings.TrimSpace(parts[1]); strings.HasPrefix(param, "q=") {
+			if v, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+				q = v
+			}
+		}
+	}
+
+	return name, q
+}
+
+var gzipWriterPool = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(io.Discard) },
+}
+
+var flateWriterPool = sync.Pool{
+	New: func() interface{} {
+		fw, _ := flate.NewWriter(io.Discard, flate.DefaultCompression)
+		return fw
+	},
+}
+
+// writeCompressed writes statusCode and body through a pooled gzip or
+// deflate writer, clearing Content-Length since the compressed size isn't
+// known up front.
+func (r *Resp) writeCompressed(statusCode int, encoding string, body io.Reader) {
+	r.w.Header().Set("Content-Encoding", encoding)
+	r.w.Header().Del("Content-Length")
+	r.w.Header().Add("Vary", "Accept-Encoding")
+	r.w.WriteHeader(statusCode)
+
+	switch encoding {
+	case "gzip":
+		gz := gzipWriterPool.Get().(*gzip.Writer)
+		gz.Reset(r.w)
+		io.Copy(gz, body)
+		gz.Close()
+		gzipWriterPool.Put(gz)
+
+	case "deflate":
+		fl := flateWriterPool.Get().(*flate.Writer)
+		fl.Reset(r.w)
+		io.Copy(fl, body)
+		fl.Close()
+		flateWriterPool.Put(fl)
+	}
+}
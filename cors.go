@@ -0,0 +1,145 @@
+package resp
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy configures Cross-Origin Resource Sharing for a Resp.
+//
+// AllowedOrigins entries are matched exactly, except "*" (any origin) and a
+// "*.example.com" suffix form (any subdomain of example.com). AllowCredentials
+// forbids "*" from ever being reflected as Access-Control-Allow-Origin, per
+// the Fetch spec: a matching request's own Origin is echoed instead. A "*"
+// entry still matches every origin, so "*" together with AllowCredentials
+// admits credentialed requests from any origin.
+type CORSPolicy struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// defaultCORSPolicy backs SetCORS(true): any origin, no credentials, the
+// headers the package has always allowed.
+var defaultCORSPolicy = &CORSPolicy{
+	AllowedOrigins: []string{"*"},
+	AllowedHeaders: []string{"Origin", "X-Requested-With", "Content-Type", "Accept"},
+}
+
+func matchOrigin(patterns []string, origin string) bool {
+	for _, pattern := range patterns {
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		if suffix := strings.TrimPrefix(pattern, "*"); suffix != pattern {
+			if strings.HasSuffix(origin, suffix) && len(origin) > len(suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func containsString(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// applyCORS sets the Access-Control-* response headers for the configured
+// policy, if any. It is called by every Write* method so CORS headers are
+// present on both successful and error responses.
+func (r *Resp) applyCORS() {
+	policy := r.cors
+	if policy == nil {
+		return
+	}
+
+	origin := ""
+	if r.req != nil {
+		origin = r.req.Header.Get("Origin")
+	}
+
+	var allowOrigin string
+	switch {
+	case origin != "" && matchOrigin(policy.AllowedOrigins, origin):
+		allowOrigin = origin
+	case !policy.AllowCredentials && containsString(policy.AllowedOrigins, "*"):
+		allowOrigin = "*"
+	default:
+		return
+	}
+
+	h := r.Header()
+	h.Set("Access-Control-Allow-Origin", allowOrigin)
+	if allowOrigin != "*" {
+		h.Add("Vary", "Origin")
+	}
+	if policy.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(policy.AllowedHeaders) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(policy.AllowedHeaders, ", "))
+	}
+	if len(policy.ExposedHeaders) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+	}
+}
+
+func HandlePreflight(w http.ResponseWriter, req *http.Request) bool {
+	return New(w).HandlePreflight(req)
+}
+
+// HandlePreflight answers an OPTIONS preflight request with the configured
+// CORS policy (the default permissive policy if none was set) and returns
+// true if it handled the request. Callers should return immediately from
+// their handler when it returns true.
+func (r *Resp) HandlePreflight(req *http.Request) bool {
+	if req.Method != http.MethodOptions || req.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+
+	r.req = req
+
+	policy := r.cors
+	if policy == nil {
+		policy = defaultCORSPolicy
+	}
+
+	origin := req.Header.Get("Origin")
+	if origin == "" || !matchOrigin(policy.AllowedOrigins, origin) {
+		if policy.AllowCredentials || !containsString(policy.AllowedOrigins, "*") {
+			r.w.WriteHeader(http.StatusForbidden)
+			return true
+		}
+	}
+
+	prevCORS := r.cors
+	r.cors = policy
+	r.applyCORS()
+	r.cors = prevCORS
+
+	methods := policy.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{
+			http.MethodGet, http.MethodHead, http.MethodPost,
+			http.MethodPut, http.MethodPatch, http.MethodDelete,
+		}
+	}
+	r.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+
+	if policy.MaxAge > 0 {
+		r.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge.Seconds())))
+	}
+
+	r.w.WriteHeader(http.StatusNoContent)
+	return true
+}
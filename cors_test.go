@@ -0,0 +1,80 @@
+package resp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchOrigin(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		origin   string
+		want     bool
+	}{
+		{name: "wildcard", patterns: []string{"*"}, origin: "https://example.com", want: true},
+		{name: "exact match", patterns: []string{"https://example.com"}, origin: "https://example.com", want: true},
+		{name: "exact mismatch", patterns: []string{"https://example.com"}, origin: "https://evil.com", want: false},
+		{name: "subdomain wildcard match", patterns: []string{"*.example.com"}, origin: "https://foo.example.com", want: true},
+		{name: "subdomain wildcard requires a subdomain", patterns: []string{"*.example.com"}, origin: "https://example.com", want: false},
+		{name: "subdomain wildcard mismatch", patterns: []string{"*.example.com"}, origin: "https://evilexample.com", want: false},
+		{name: "no patterns", patterns: nil, origin: "https://example.com", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchOrigin(tt.patterns, tt.origin); got != tt.want {
+				t.Fatalf("matchOrigin(%v, %q) = %v, want %v", tt.patterns, tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlePreflightAllowsConfiguredOrigin(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("Origin", "https://example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+
+	r := New(rec)
+	r.SetCORSPolicy(&CORSPolicy{AllowedOrigins: []string{"https://example.com"}})
+
+	if !r.HandlePreflight(req) {
+		t.Fatal("HandlePreflight returned false for a valid preflight request")
+	}
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://example.com" {
+		t.Fatalf("Access-Control-Allow-Origin = %q, want %q", got, "https://example.com")
+	}
+}
+
+func TestHandlePreflightRejectsDisallowedOrigin(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+
+	r := New(rec)
+	r.SetCORSPolicy(&CORSPolicy{AllowedOrigins: []string{"https://example.com"}})
+
+	if !r.HandlePreflight(req) {
+		t.Fatal("HandlePreflight returned false for an OPTIONS preflight request")
+	}
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestHandlePreflightIgnoresNonPreflightOptions(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodOptions, "/", nil)
+
+	r := New(rec)
+
+	if r.HandlePreflight(req) {
+		t.Fatal("HandlePreflight handled an OPTIONS request with no Access-Control-Request-Method")
+	}
+}
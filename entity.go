@@ -0,0 +1,142 @@
+package resp
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Encoder marshals v and writes it to w. Registered encoders are selected by
+// WriteEntity based on the request's Accept header.
+type Encoder func(w io.Writer, v interface{}) error
+
+var encoders = map[string]Encoder{
+	"application/json": func(w io.Writer, v interface{}) error {
+		return json.NewEncoder(w).Encode(v)
+	},
+	"application/xml": func(w io.Writer, v interface{}) error {
+		return xml.NewEncoder(w).Encode(v)
+	},
+}
+
+// encoderOrder tracks registration order so wildcard Accept ranges
+// ("application/*") resolve deterministically instead of via Go's
+// randomized map iteration.
+var encoderOrder = []string{"application/json", "application/xml"}
+
+// defaultMediaType is used when the client sends no Accept header, or
+// accepts anything ("*/*"), mirroring WriteJSON's existing behavior.
+const defaultMediaType = "application/json"
+
+// RegisterEncoder registers enc as the writer for mediaType, so WriteEntity
+// can negotiate it via the Accept header. Registering over "application/json"
+// or "application/xml" replaces the built-in encoder.
+func RegisterEncoder(mediaType string, enc Encoder) {
+	if _, exists := encoders[mediaType]; !exists {
+		encoderOrder = append(encoderOrder, mediaType)
+	}
+	encoders[mediaType] = enc
+}
+
+func WriteEntity(w http.ResponseWriter, req *http.Request, statusCode int, v interface{}) {
+	NewWithRequest(w, req).WriteEntity(statusCode, v)
+}
+
+// WriteEntity marshals v with the encoder negotiated from the request's
+// Accept header (defaulting to JSON) and writes it with the matching
+// Content-Type. It returns 406 Not Acceptable through the error handler when
+// no registered encoder satisfies the request.
+func (r *Resp) WriteEntity(statusCode int, v interface{}) {
+	r.Header().Add("Vary", "Accept")
+
+	mediaType, enc := negotiateEncoder(r.req)
+	if enc == nil {
+		r.WriteError(&statusError{status: http.StatusNotAcceptable, msg: "resp: no acceptable media type for response"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := enc(&buf, v); err != nil {
+		r.WriteError(err)
+		return
+	}
+
+	r.w.Header().Set("Content-Type", mediaType)
+	r.w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	r.Copy(statusCode, &buf)
+}
+
+// negotiateEncoder picks the registered encoder with the highest-ranked
+// media type the request's Accept header allows, preferring defaultMediaType
+// when the header is absent or accepts anything.
+func negotiateEncoder(req *http.Request) (string, Encoder) {
+	accept := ""
+	if req != nil {
+		accept = req.Header.Get("Accept")
+	}
+
+	if accept == "" {
+		return defaultMediaType, encoders[defaultMediaType]
+	}
+
+	for _, accepted := range parseAccept(accept) {
+		if accepted.mediaType == "*/*" {
+			return defaultMediaType, encoders[defaultMediaType]
+		}
+		if enc, ok := encoders[accepted.mediaType]; ok {
+			return accepted.mediaType, enc
+		}
+		if strings.HasSuffix(accepted.mediaType, "/*") {
+			prefix := strings.TrimSuffix(accepted.mediaType, "*")
+			for _, mediaType := range encoderOrder {
+				if strings.HasPrefix(mediaType, prefix) {
+					return mediaType, encoders[mediaType]
+				}
+			}
+		}
+	}
+
+	return "", nil
+}
+
+type acceptedType struct {
+	mediaType string
+	q         float64
+}
+
+// parseAccept parses an Accept header into its media types, ranked by
+// descending q-value (ties keep header order). Entries with q<=0 are
+// dropped: a "q=0" explicitly marks a media type unacceptable.
+func parseAccept(header string) []acceptedType {
+	fields := strings.Split(header, ",")
+	accepted := make([]acceptedType, 0, len(fields))
+
+	for _, field := range fields {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(field))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if qs, ok := params["q"]; ok {
+			if v, err := strconv.ParseFloat(qs, 64); err == nil {
+				q = v
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		accepted = append(accepted, acceptedType{mediaType: mediaType, q: q})
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].q > accepted[j].q })
+
+	return accepted
+}
@@ -0,0 +1,81 @@
+package resp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseAccept(t *testing.T) {
+	accepted := parseAccept("text/html;q=0.5, application/json, application/xml;q=0.9")
+
+	want := []string{"application/json", "application/xml", "text/html"}
+	if len(accepted) != len(want) {
+		t.Fatalf("parseAccept returned %d entries, want %d", len(accepted), len(want))
+	}
+	for i, mediaType := range want {
+		if accepted[i].mediaType != mediaType {
+			t.Fatalf("accepted[%d].mediaType = %q, want %q", i, accepted[i].mediaType, mediaType)
+		}
+	}
+}
+
+func TestNegotiateEncoderWildcardIsDeterministic(t *testing.T) {
+	originalEncoder := encoders["application/msgpack"]
+	originalOrder := append([]string(nil), encoderOrder...)
+	t.Cleanup(func() {
+		if originalEncoder == nil {
+			delete(encoders, "application/msgpack")
+		} else {
+			encoders["application/msgpack"] = originalEncoder
+		}
+		encoderOrder = originalOrder
+	})
+
+	RegisterEncoder("application/msgpack", func(io.Writer, interface{}) error { return nil })
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/*")
+
+	mediaType, _ := negotiateEncoder(req)
+	if mediaType != "application/json" {
+		t.Fatalf("negotiateEncoder(application/*) = %q, want %q (registration order)", mediaType, "application/json")
+	}
+
+	for i := 0; i < 20; i++ {
+		got, _ := negotiateEncoder(req)
+		if got != mediaType {
+			t.Fatalf("negotiateEncoder is nondeterministic: got %q, then %q", mediaType, got)
+		}
+	}
+}
+
+func TestNegotiateEncoderDefaultsToJSON(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	mediaType, enc := negotiateEncoder(req)
+	if mediaType != defaultMediaType || enc == nil {
+		t.Fatalf("negotiateEncoder(no Accept header) = (%q, %v), want (%q, non-nil)", mediaType, enc, defaultMediaType)
+	}
+}
+
+func TestNegotiateEncoderNoMatch(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/x-does-not-exist")
+
+	mediaType, enc := negotiateEncoder(req)
+	if mediaType != "" || enc != nil {
+		t.Fatalf("negotiateEncoder(unmatched Accept) = (%q, %v), want (\"\", nil)", mediaType, enc)
+	}
+}
+
+func TestNegotiateEncoderHonorsQZero(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/json;q=0")
+
+	mediaType, enc := negotiateEncoder(req)
+	if mediaType != "" || enc != nil {
+		t.Fatalf("negotiateEncoder(application/json;q=0) = (%q, %v), want (\"\", nil)", mediaType, enc)
+	}
+}
@@ -0,0 +1,313 @@
+package resp
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServeFile writes path to w, honoring Range, If-Modified-Since, If-None-Match
+// and If-Range headers on req. It is the request-aware counterpart of
+// WriteFile and always responds with a 200 (or 206/304/416 as the request
+// demands).
+func ServeFile(w http.ResponseWriter, req *http.Request, path string) {
+	New(w).WriteFileRequest(req, http.StatusOK, path)
+}
+
+// WriteFileRequest behaves like Resp.WriteFile but has access to req, so it
+// can honor Range, If-Modified-Since, If-None-Match and If-Range headers.
+func WriteFileRequest(w http.ResponseWriter, req *http.Request, statusCode int, path string) {
+	New(w).WriteFileRequest(req, statusCode, path)
+}
+
+func (r *Resp) WriteFileRequest(req *http.Request, statusCode int, path string) {
+	r.req = req
+	r.WriteFile(statusCode, path)
+}
+
+// httpRange is a single byte range resolved against a known content size.
+type httpRange struct {
+	start  int64
+	length int64
+}
+
+func (ra httpRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", ra.start, ra.start+ra.length-1, size)
+}
+
+func (ra httpRange) mimeHeader(contentType string, size int64) textproto.MIMEHeader {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Range", ra.contentRange(size))
+	return h
+}
+
+// serveContent writes body to r.w, applying conditional and range handling
+// based on r.req. modtime may be the zero Value when it is unknown, in which
+// case Last-Modified, If-Modified-Since and If-Range-by-date are skipped but
+// ETag, If-None-Match and Range are still honored.
+func (r *Resp) serveContent(statusCode int, modtime time.Time, size int64, body io.ReadSeeker) {
+	req := r.req
+	w := r.w
+
+	// CORS headers are applied by the caller (WriteFile) before reaching
+	// here; serveContent must not apply them a second time.
+
+	if !modtime.IsZero() {
+		w.Header().Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
+	}
+
+	etag := fmt.Sprintf("\"%x-%x\"", modtime.UnixNano(), size)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Accept-Ranges", "bytes")
+
+	if checkPreconditions(w, req, modtime, etag) {
+		return
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		head := make([]byte, headerSize)
+		n, _ := io.ReadFull(body, head)
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			r.WriteError(err)
+			return
+		}
+		contentType, err := detectContentTypeHead(head[:n])
+		if err != nil {
+			r.WriteError(err)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+	}
+	contentType := w.Header().Get("Content-Type")
+
+	ranges, err := parseRange(req.Header.Get("Range"), size)
+	if err == errNoOverlap {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	if err != nil {
+		// Malformed Range header: ignore it and serve the full body, as
+		// net/http.ServeContent does.
+		ranges = nil
+	}
+
+	if ir := req.Header.Get("If-Range"); ir != "" && !ifRangeMatches(ir, modtime, etag) {
+		ranges = nil
+	}
+
+	switch {
+	case len(ranges) == 0:
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			r.WriteError(err)
+			return
+		}
+		w.WriteHeader(statusCode)
+		if req.Method != http.MethodHead {
+			io.Copy(w, body)
+		}
+
+	case len(ranges) == 1:
+		ra := ranges[0]
+		w.Header().Set("Content-Range", ra.contentRange(size))
+		w.Header().Set("Content-Length", strconv.FormatInt(ra.length, 10))
+		if _, err := body.Seek(ra.start, io.SeekStart); err != nil {
+			r.WriteError(err)
+			return
+		}
+		w.WriteHeader(http.StatusPartialContent)
+		if req.Method != http.MethodHead {
+			io.CopyN(w, body, ra.length)
+		}
+
+	default:
+		write, boundary, contentLength := multipartRangeWriter(ranges, contentType, size)
+		w.Header().Set("Content-Type", "multipart/byteranges; boundary="+boundary)
+		w.Header().Set("Content-Length", strconv.FormatInt(contentLength, 10))
+		w.WriteHeader(http.StatusPartialContent)
+		if req.Method != http.MethodHead {
+			write(w, body)
+		}
+	}
+}
+
+// checkPreconditions evaluates If-None-Match and If-Modified-Since, writing a
+// 304 response and returning true when the client's cached copy is fresh.
+func checkPreconditions(w http.ResponseWriter, req *http.Request, modtime time.Time, etag string) bool {
+	notModified := func() bool {
+		w.Header().Del("Content-Type")
+		w.Header().Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		if etagMatches(inm, etag) {
+			return notModified()
+		}
+		return false
+	}
+
+	if !modtime.IsZero() {
+		if ims := req.Header.Get("If-Modified-Since"); ims != "" {
+			if t, err := http.ParseTime(ims); err == nil && !modtime.Truncate(time.Second).After(t) {
+				return notModified()
+			}
+		}
+	}
+
+	return false
+}
+
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		if tag := strings.TrimSpace(tag); tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// ifRangeMatches reports whether the If-Range validator still matches the
+// current representation, so a Range request may be honored as a partial
+// response instead of falling back to a full one.
+func ifRangeMatches(header string, modtime time.Time, etag string) bool {
+	if strings.HasPrefix(header, `"`) {
+		return header == etag
+	}
+	t, err := http.ParseTime(header)
+	return err == nil && !modtime.IsZero() && modtime.Truncate(time.Second).Equal(t)
+}
+
+var errNoOverlap = fmt.Errorf("invalid range: failed to overlap")
+
+// parseRange parses a Range header as described in RFC 7233, resolving each
+// range against size. It returns nil ranges and a nil error when header is
+// empty, and errNoOverlap when every requested range falls outside size.
+func parseRange(header string, size int64) ([]httpRange, error) {
+	if header == "" {
+		return nil, nil
+	}
+
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return nil, fmt.Errorf("invalid range: does not start with %q", prefix)
+	}
+
+	var ranges []httpRange
+	noOverlap := false
+	for _, part := range strings.Split(header[len(prefix):], ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		i := strings.IndexByte(part, '-')
+		if i < 0 {
+			return nil, fmt.Errorf("invalid range: %q", part)
+		}
+		start, end := strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+1:])
+
+		var ra httpRange
+		switch {
+		case start == "" && end == "":
+			return nil, fmt.Errorf("invalid range: %q", part)
+
+		case start == "":
+			// suffix range: "-N" means the last N bytes.
+			n, err := strconv.ParseInt(end, 10, 64)
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid range: %q", part)
+			}
+			if n > size {
+				n = size
+			}
+			ra.start = size - n
+			ra.length = n
+
+		default:
+			i, err := strconv.ParseInt(start, 10, 64)
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("invalid range: %q", part)
+			}
+			if i >= size {
+				noOverlap = true
+				continue
+			}
+			ra.start = i
+			if end == "" {
+				ra.length = size - ra.start
+			} else {
+				j, err := strconv.ParseInt(end, 10, 64)
+				if err != nil || ra.start > j {
+					return nil, fmt.Errorf("invalid range: %q", part)
+				}
+				if j >= size {
+					j = size - 1
+				}
+				ra.length = j - ra.start + 1
+			}
+		}
+
+		ranges = append(ranges, ra)
+	}
+
+	if len(ranges) == 0 && noOverlap {
+		return nil, errNoOverlap
+	}
+
+	return ranges, nil
+}
+
+// multipartRangeWriter returns a function that streams ranges from body as a
+// multipart/byteranges body, along with the boundary and total Content-Length
+// it will produce. The Content-Length is computed up front by writing the
+// part headers to a discarding counter, mirroring net/http.ServeContent.
+func multipartRangeWriter(ranges []httpRange, contentType string, size int64) (write func(io.Writer, io.ReadSeeker), boundary string, contentLength int64) {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].start < ranges[j].start })
+
+	var counter countingWriter
+	mw := multipart.NewWriter(&counter)
+	boundary = mw.Boundary()
+	for _, ra := range ranges {
+		mw.CreatePart(ra.mimeHeader(contentType, size))
+		contentLength += ra.length
+	}
+	mw.Close()
+	contentLength += int64(counter.n)
+
+	write = func(w io.Writer, body io.ReadSeeker) {
+		mw := multipart.NewWriter(w)
+		mw.SetBoundary(boundary)
+		for _, ra := range ranges {
+			part, err := mw.CreatePart(ra.mimeHeader(contentType, size))
+			if err != nil {
+				return
+			}
+			if _, err := body.Seek(ra.start, io.SeekStart); err != nil {
+				return
+			}
+			if _, err := io.CopyN(part, body, ra.length); err != nil {
+				return
+			}
+		}
+		mw.Close()
+	}
+
+	return write, boundary, contentLength
+}
+
+type countingWriter struct{ n int }
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += len(p)
+	return len(p), nil
+}
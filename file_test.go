@@ -0,0 +1,74 @@
+package resp
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	const size = int64(100)
+
+	tests := []struct {
+		name          string
+		header        string
+		want          []httpRange
+		wantNoOverlap bool
+		wantErr       bool
+	}{
+		{name: "empty header", header: "", want: nil},
+		{name: "single range", header: "bytes=0-49", want: []httpRange{{start: 0, length: 50}}},
+		{name: "open ended", header: "bytes=50-", want: []httpRange{{start: 50, length: 50}}},
+		{name: "suffix range", header: "bytes=-10", want: []httpRange{{start: 90, length: 10}}},
+		{name: "suffix larger than size", header: "bytes=-1000", want: []httpRange{{start: 0, length: 100}}},
+		{name: "end clamped to size", header: "bytes=90-1000", want: []httpRange{{start: 90, length: 10}}},
+		{name: "multiple ranges", header: "bytes=0-9,20-29", want: []httpRange{{start: 0, length: 10}, {start: 20, length: 10}}},
+		{name: "start beyond size", header: "bytes=200-300", wantNoOverlap: true},
+		{name: "missing bytes prefix", header: "items=0-10", wantErr: true},
+		{name: "missing dash", header: "bytes=10", wantErr: true},
+		{name: "empty range", header: "bytes=-", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRange(tt.header, size)
+
+			switch {
+			case tt.wantNoOverlap:
+				if err != errNoOverlap {
+					t.Fatalf("parseRange(%q) error = %v, want errNoOverlap", tt.header, err)
+				}
+
+			case tt.wantErr:
+				if err == nil {
+					t.Fatalf("parseRange(%q) error = nil, want an error", tt.header)
+				}
+
+			default:
+				if err != nil {
+					t.Fatalf("parseRange(%q) unexpected error: %v", tt.header, err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Fatalf("parseRange(%q) = %+v, want %+v", tt.header, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMultipartRangeWriterContentLength(t *testing.T) {
+	data := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	ranges := []httpRange{{start: 0, length: 5}, {start: 10, length: 8}}
+
+	write, boundary, contentLength := multipartRangeWriter(ranges, "text/plain", int64(len(data)))
+	if boundary == "" {
+		t.Fatal("multipartRangeWriter returned an empty boundary")
+	}
+
+	var buf bytes.Buffer
+	write(&buf, bytes.NewReader(data))
+
+	if int64(buf.Len()) != contentLength {
+		t.Fatalf("computed Content-Length %d does not match actual body length %d", contentLength, buf.Len())
+	}
+}
@@ -0,0 +1,148 @@
+package resp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Problem is a "problem details" error as described by RFC 7807. It
+// satisfies the error interface, so it flows through WriteError and
+// DetectStatusCode like any other error, but renders as
+// application/problem+json when the request's Accept header prefers JSON
+// (see prefersProblemJSON).
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+// NewProblem builds a Problem for status, with Title set from
+// http.StatusText(status) and Detail set to detail.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return fmt.Sprintf("%s: %s", p.Title, p.Detail)
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	if p.Type != "" {
+		m["type"] = p.Type
+	}
+	if p.Title != "" {
+		m["title"] = p.Title
+	}
+	if p.Status != 0 {
+		m["status"] = p.Status
+	}
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	return json.Marshal(m)
+}
+
+// prefersProblemJSON reports whether req's Accept header ranks a JSON media
+// type above a text one, so WriteError knows whether to render a Problem as
+// application/problem+json or fall back to its plain-text rendering.
+func prefersProblemJSON(req *http.Request) bool {
+	accept := req.Header.Get("Accept")
+	if accept == "" {
+		return false
+	}
+
+	for _, accepted := range parseAccept(accept) {
+		switch accepted.mediaType {
+		case "application/problem+json", "application/json":
+			return true
+		case "text/html", "text/plain", "*/*":
+			return false
+		}
+	}
+
+	return false
+}
+
+func (r *Resp) writeProblem(err error) {
+	status := DetectStatusCode(err)
+
+	p, ok := err.(*Problem)
+	if !ok {
+		p = NewProblem(status, err.Error())
+	}
+
+	body, merr := json.Marshal(p)
+	if merr != nil {
+		HandleError(r.w, err)
+		return
+	}
+
+	r.w.Header().Set("Content-Type", "application/problem+json")
+	r.w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	r.w.WriteHeader(status)
+	r.w.Write(body)
+}
+
+func WriteBadRequest(w http.ResponseWriter, detail string) {
+	New(w).WriteBadRequest(detail)
+}
+
+// WriteBadRequest writes a 400 Problem through WriteError.
+func (r *Resp) WriteBadRequest(detail string) {
+	r.WriteError(NewProblem(http.StatusBadRequest, detail))
+}
+
+func WriteNotFound(w http.ResponseWriter, detail string) {
+	New(w).WriteNotFound(detail)
+}
+
+// WriteNotFound writes a 404 Problem through WriteError.
+func (r *Resp) WriteNotFound(detail string) {
+	r.WriteError(NewProblem(http.StatusNotFound, detail))
+}
+
+func WriteForbidden(w http.ResponseWriter, detail string) {
+	New(w).WriteForbidden(detail)
+}
+
+// WriteForbidden writes a 403 Problem through WriteError.
+func (r *Resp) WriteForbidden(detail string) {
+	r.WriteError(NewProblem(http.StatusForbidden, detail))
+}
+
+func WriteConflict(w http.ResponseWriter, detail string) {
+	New(w).WriteConflict(detail)
+}
+
+// WriteConflict writes a 409 Problem through WriteError.
+func (r *Resp) WriteConflict(detail string) {
+	r.WriteError(NewProblem(http.StatusConflict, detail))
+}
+
+func WriteInternalError(w http.ResponseWriter, detail string) {
+	New(w).WriteInternalError(detail)
+}
+
+// WriteInternalError writes a 500 Problem through WriteError.
+func (r *Resp) WriteInternalError(detail string) {
+	r.WriteError(NewProblem(http.StatusInternalServerError, detail))
+}
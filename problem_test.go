@@ -0,0 +1,25 @@
+package resp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPrefersProblemJSONHonorsQZero(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "application/json;q=0, text/plain")
+
+	if prefersProblemJSON(req) {
+		t.Fatal("prefersProblemJSON(application/json;q=0) = true, want false")
+	}
+}
+
+func TestPrefersProblemJSONPrefersJSON(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/plain;q=0.5, application/json")
+
+	if !prefersProblemJSON(req) {
+		t.Fatal("prefersProblemJSON(application/json over text/plain;q=0.5) = false, want true")
+	}
+}
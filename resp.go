@@ -10,19 +10,27 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/h2non/filetype/types"
 	"github.com/thamaji/terrors"
 	"gopkg.in/h2non/filetype.v1"
+	"gopkg.in/h2non/filetype.v1/types"
 )
 
 func New(w http.ResponseWriter) *Resp {
 	return &Resp{w: w, errorHandler: DefaultErrorHandler}
 }
 
+// NewWithRequest is like New but also gives the Resp access to req, which is
+// required by request-aware features such as Range-aware file serving, CORS
+// origin matching and WriteEntity's content negotiation.
+func NewWithRequest(w http.ResponseWriter, req *http.Request) *Resp {
+	return &Resp{w: w, req: req, errorHandler: DefaultErrorHandler}
+}
+
 type Resp struct {
 	w            http.ResponseWriter
+	req          *http.Request
 	errorHandler ErrorHandler
-	cors         bool
+	cors         *CORSPolicy
 }
 
 func (r *Resp) Header() http.Header {
@@ -33,15 +41,45 @@ func (r *Resp) SetErrorHandler(handler ErrorHandler) {
 	r.errorHandler = handler
 }
 
+// SetCORS is a thin shim over SetCORSPolicy for callers that only need a
+// permissive, uncredentialed CORS policy: cors(true) allows any origin,
+// cors(false) disables CORS handling entirely.
 func (r *Resp) SetCORS(cors bool) {
-	r.cors = cors
+	if !cors {
+		r.cors = nil
+		return
+	}
+	r.cors = defaultCORSPolicy
+}
+
+// SetCORSPolicy configures per-instance CORS handling. A nil policy disables
+// CORS handling, matching SetCORS(false).
+func (r *Resp) SetCORSPolicy(policy *CORSPolicy) {
+	r.cors = policy
 }
 
 type ErrorHandler func(http.ResponseWriter, error)
 
 var DefaultErrorHandler ErrorHandler = HandleError
 
+// statusError pins an error to a specific HTTP status code, for errors
+// (like a failed content negotiation) that don't fit the terrors taxonomy.
+type statusError struct {
+	status int
+	msg    string
+}
+
+func (e *statusError) Error() string { return e.msg }
+
 func DetectStatusCode(err error) int {
+	if se, ok := err.(*statusError); ok {
+		return se.status
+	}
+
+	if p, ok := err.(*Problem); ok {
+		return p.Status
+	}
+
 	if os.IsNotExist(err) {
 		return http.StatusNotFound
 	}
@@ -79,10 +117,13 @@ func WriteError(w http.ResponseWriter, err error) {
 }
 
 func (r *Resp) WriteError(err error) {
-	if r.cors {
-		r.Header().Set("Access-Control-Allow-Origin", "*")
-		r.Header().Set("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept")
+	r.applyCORS()
+
+	if r.req != nil && prefersProblemJSON(r.req) {
+		r.writeProblem(err)
+		return
 	}
+
 	if r.errorHandler != nil {
 		r.errorHandler(r.w, err)
 	} else {
@@ -95,10 +136,7 @@ func WriteUnauthorized(w http.ResponseWriter, realm string) {
 }
 
 func (r *Resp) WriteUnauthorized(realm string) {
-	if r.cors {
-		r.Header().Set("Access-Control-Allow-Origin", "*")
-		r.Header().Set("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept")
-	}
+	r.applyCORS()
 	r.w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`"`)
 	r.w.WriteHeader(http.StatusUnauthorized)
 	fmt.Fprint(r.w, http.StatusText(http.StatusUnauthorized))
@@ -122,6 +160,12 @@ func (r *Resp) WriteFile(statusCode int, path string) {
 		return
 	}
 
+	if r.req != nil {
+		r.applyCORS()
+		r.serveContent(statusCode, fi.ModTime(), fi.Size(), f)
+		return
+	}
+
 	r.w.Header().Set("Last-Modified", fi.ModTime().Format(time.RFC1123))
 	r.w.Header().Set("Content-Length", strconv.FormatInt(fi.Size(), 10))
 	r.Copy(statusCode, f)
@@ -170,11 +214,12 @@ func Copy(w http.ResponseWriter, statusCode int, body io.Reader) {
 	New(w).Copy(statusCode, body)
 }
 
+// Copy writes statusCode and body as-is. Unlike WriteFile, it never engages
+// Range/conditional-request handling: body is an arbitrary io.Reader, not
+// necessarily a stable, seekable resource, so callers that want Range and
+// ETag support must go through WriteFile/ServeFile instead.
 func (r *Resp) Copy(statusCode int, body io.Reader) {
-	if r.cors {
-		r.Header().Set("Access-Control-Allow-Origin", "*")
-		r.Header().Set("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept")
-	}
+	r.applyCORS()
 
 	if r.w.Header().Get("Content-Type") == "" {
 		reader, contentType, err := DetectContentType(body)
@@ -188,6 +233,13 @@ func (r *Resp) Copy(statusCode int, body io.Reader) {
 		r.w.Header().Set("Content-Type", contentType)
 	}
 
+	if r.req != nil && isCompressible(r.w.Header().Get("Content-Type")) {
+		if encoding := negotiateEncoding(r.req); encoding != "" {
+			r.writeCompressed(statusCode, encoding, body)
+			return
+		}
+	}
+
 	r.w.WriteHeader(statusCode)
 	io.Copy(r.w, body)
 }
@@ -199,9 +251,38 @@ func DetectContentType(r io.Reader) (io.Reader, string, error) {
 	l, _ := io.ReadFull(r, buf[:])
 	head := buf[:l]
 	r = io.MultiReader(bytes.NewReader(head), r)
+	contentType, err := detectContentTypeHead(head)
+	return r, contentType, err
+}
+
+// Matcher inspects the head of a body and reports the MIME type it
+// recognizes, if any. Matchers run after filetype.Match and before the
+// net/http.DetectContentType fallback, so they can add domain-specific magic
+// (Parquet, glTF, ...) that neither library knows about.
+type Matcher func(head []byte) (mimeType string, ok bool)
+
+var matchers []Matcher
+
+// RegisterMatcher adds m to the chain DetectContentType consults when
+// filetype.Match can't identify a body.
+func RegisterMatcher(m Matcher) {
+	matchers = append(matchers, m)
+}
+
+func detectContentTypeHead(head []byte) (string, error) {
 	t, err := filetype.Match(head)
-	if err != nil || t == types.Unknown {
-		return r, "application/octet-stream", err
+	if err != nil {
+		return "application/octet-stream", err
+	}
+	if t != types.Unknown {
+		return t.MIME.Value, nil
 	}
-	return r, t.MIME.Value, nil
+
+	for _, match := range matchers {
+		if mimeType, ok := match(head); ok {
+			return mimeType, nil
+		}
+	}
+
+	return http.DetectContentType(head), nil
 }